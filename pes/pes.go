@@ -26,14 +26,56 @@ package pes
 
 import "github.com/potterxu/gots/v2/packet"
 
-// AlignedPUSI checks for a PUSI with aligned flag set and returns a bool
-// indicating a match when true, as well as the bytes for the PES data
-func AlignedPUSI(pkt *packet.Packet) ([]byte, bool) {
+// AlignmentStatus describes what AlignedPUSI learned about a packet's PES
+// payload.
+type AlignmentStatus int
+
+const (
+	// NoPUSI means pkt did not carry a payload_unit_start_indicator, so it
+	// cannot start a PES unit.
+	NoPUSI AlignmentStatus = iota
+	// PUSIButNotAligned means pkt starts a PES unit, but the PES header's
+	// data_alignment_indicator is not set.
+	PUSIButNotAligned
+	// Aligned means pkt starts a PES unit whose data is aligned.
+	Aligned
+	// NeedsMorePackets means pkt starts a PES unit, but pkt's payload alone
+	// didn't contain enough bytes to parse the full PES header. Use a
+	// Reassembler to span packets before parsing it.
+	NeedsMorePackets
+	// ParseFailed means pkt carried a payload_unit_start_indicator, but its
+	// PES header was malformed (bad start code, or some other error more
+	// bytes can't fix). err holds the reason.
+	ParseFailed
+)
+
+// AlignedPUSI checks pkt for a payload_unit_start_indicator, parses its PES
+// header if one is present, and reports whether the PES data it carries is
+// aligned. header and data are only valid when status is PUSIButNotAligned
+// or Aligned. A set payload_unit_start_indicator always yields one of
+// PUSIButNotAligned, Aligned, NeedsMorePackets, or ParseFailed - never
+// NoPUSI - so callers can tell a packet with no PUSI apart from one whose
+// PES header simply failed to parse.
+func AlignedPUSI(pkt *packet.Packet) (header PESHeader, data []byte, status AlignmentStatus, err error) {
 	if !pkt.PayloadUnitStartIndicator() {
-	} else if pesHeaderBytes, err := packet.PESHeader(pkt); err != nil {
-	} else if pesHeader, err := NewPESHeader(pesHeaderBytes); err != nil {
-	} else if pesHeader != nil && pesHeader.DataAligned() {
-		return pesHeader.Data(), true
+		return nil, nil, NoPUSI, nil
+	}
+
+	pesHeaderBytes, err := packet.PESHeader(pkt)
+	if err != nil {
+		return nil, nil, ParseFailed, err
+	}
+
+	header, err = NewPESHeader(pesHeaderBytes)
+	if err == ErrNeedsMoreBytes {
+		return nil, nil, NeedsMorePackets, nil
+	}
+	if err != nil {
+		return nil, nil, ParseFailed, err
+	}
+
+	if !header.DataAligned() {
+		return header, header.Data(), PUSIButNotAligned, nil
 	}
-	return nil, false
+	return header, header.Data(), Aligned, nil
 }
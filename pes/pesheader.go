@@ -0,0 +1,199 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pes
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	// ErrPESParse is returned when a PES header is malformed in a way more
+	// bytes cannot fix (bad start code, marker bits not set, and so on).
+	ErrPESParse = errors.New("pes: invalid PES header")
+	// ErrNeedsMoreBytes is returned by NewPESHeader when b is too short to
+	// contain the optional PES header it claims to have; the caller should
+	// retry once more of the PES packet (i.e. more TS packets) is available.
+	ErrNeedsMoreBytes = errors.New("pes: need more bytes to parse PES header")
+)
+
+// streamIDsWithoutOptionalHeader are the stream_id values (ISO/IEC 13818-1
+// Table 2-18) that are never followed by the optional PES header fields
+// (PTS/DTS, ESCR, ...) - their payload starts immediately after
+// PES_packet_length.
+var streamIDsWithoutOptionalHeader = map[uint8]bool{
+	0xBC: true, // program_stream_map
+	0xBE: true, // padding_stream
+	0xBF: true, // private_stream_2
+	0xF0: true, // ECM
+	0xF1: true, // EMM
+	0xF2: true, // DSMCC_stream
+	0xF8: true, // ITU-T Rec. H.222.1 type E stream
+	0xFF: true, // program_stream_directory
+}
+
+// PESHeader is a parsed PES packet header.
+type PESHeader interface {
+	// StreamID is the stream_id field identifying the kind of elementary
+	// stream this PES packet carries.
+	StreamID() uint8
+	// PacketLength is the PES_packet_length field; 0 means "unbounded",
+	// which is only legal for video streams.
+	PacketLength() uint16
+	// DataAligned reports the data_alignment_indicator.
+	DataAligned() bool
+	HasPTS() bool
+	PTS() uint64
+	HasDTS() bool
+	DTS() uint64
+	HasESCR() bool
+	ESCR() uint64
+	// HasExtension reports the PES_extension_flag.
+	HasExtension() bool
+	// Data is the PES packet's payload, following the header.
+	Data() []byte
+}
+
+type pesHeader struct {
+	streamID     uint8
+	packetLength uint16
+	dataAligned  bool
+	hasPTS       bool
+	pts          uint64
+	hasDTS       bool
+	dts          uint64
+	hasESCR      bool
+	escr         uint64
+	hasExtension bool
+	data         []byte
+}
+
+// NewPESHeader parses a PES header from b, the bytes of a PES packet
+// starting at its 3-byte start_code_prefix. It returns ErrNeedsMoreBytes if
+// b is truncated partway through the optional header, so a caller spanning
+// packets (see Reassembler) knows to wait for more data rather than treat
+// the packet as malformed.
+func NewPESHeader(b []byte) (PESHeader, error) {
+	if len(b) < 6 || b[0] != 0x00 || b[1] != 0x00 || b[2] != 0x01 {
+		return nil, ErrPESParse
+	}
+
+	h := &pesHeader{
+		streamID:     b[3],
+		packetLength: binary.BigEndian.Uint16(b[4:6]),
+	}
+
+	if streamIDsWithoutOptionalHeader[h.streamID] {
+		h.data = b[6:]
+		return h, nil
+	}
+
+	if len(b) < 9 {
+		return nil, ErrNeedsMoreBytes
+	}
+
+	h.dataAligned = b[6]&0x04 != 0
+	ptsDtsFlags := b[7] >> 6
+	escrFlag := b[7]&0x20 != 0
+	h.hasExtension = b[7]&0x01 != 0
+	headerDataLength := int(b[8])
+
+	offset := 9
+	if ptsDtsFlags&0x2 != 0 {
+		if len(b) < offset+5 {
+			return nil, ErrNeedsMoreBytes
+		}
+		h.hasPTS = true
+		h.pts = parseTimestamp(b[offset : offset+5])
+		offset += 5
+
+		if ptsDtsFlags&0x1 != 0 {
+			if len(b) < offset+5 {
+				return nil, ErrNeedsMoreBytes
+			}
+			h.hasDTS = true
+			h.dts = parseTimestamp(b[offset : offset+5])
+			offset += 5
+		}
+	}
+
+	if escrFlag {
+		if len(b) < offset+6 {
+			return nil, ErrNeedsMoreBytes
+		}
+		h.hasESCR = true
+		h.escr = parseESCR(b[offset : offset+6])
+		offset += 6
+	}
+
+	dataStart := 9 + headerDataLength
+	if len(b) < dataStart {
+		return nil, ErrNeedsMoreBytes
+	}
+	h.data = b[dataStart:]
+
+	return h, nil
+}
+
+func (h *pesHeader) StreamID() uint8      { return h.streamID }
+func (h *pesHeader) PacketLength() uint16 { return h.packetLength }
+func (h *pesHeader) DataAligned() bool    { return h.dataAligned }
+func (h *pesHeader) HasPTS() bool         { return h.hasPTS }
+func (h *pesHeader) PTS() uint64          { return h.pts }
+func (h *pesHeader) HasDTS() bool         { return h.hasDTS }
+func (h *pesHeader) DTS() uint64          { return h.dts }
+func (h *pesHeader) HasESCR() bool        { return h.hasESCR }
+func (h *pesHeader) ESCR() uint64         { return h.escr }
+func (h *pesHeader) HasExtension() bool   { return h.hasExtension }
+func (h *pesHeader) Data() []byte         { return h.data }
+
+// parseTimestamp decodes a 33-bit, 90kHz PTS or DTS from its 5-byte encoding
+// (ISO/IEC 13818-1 2.4.3.7).
+func parseTimestamp(b []byte) uint64 {
+	ts := uint64(b[0]&0x0e) << 29
+	ts |= uint64(b[1]) << 22
+	ts |= uint64(b[2]&0xfe) << 14
+	ts |= uint64(b[3]) << 7
+	ts |= uint64(b[4]&0xfe) >> 1
+	return ts
+}
+
+// parseESCR decodes a 33-bit base (90kHz) plus 9-bit extension ESCR from its
+// 6-byte encoding (ISO/IEC 13818-1 2.4.3.5), returning the combined 27MHz
+// value the same way packet.PCR does for the transport-level PCR.
+func parseESCR(b []byte) uint64 {
+	base := uint64(b[0]&0x38) << 27
+	base |= uint64(b[0]&0x03) << 28
+	base |= uint64(b[1]) << 20
+	base |= uint64(b[2]&0xf8) << 12
+	base |= uint64(b[2]&0x03) << 13
+	base |= uint64(b[3]) << 5
+	base |= uint64(b[4]&0xf8) >> 3
+
+	ext := uint64(b[4]&0x03) << 7
+	ext |= uint64(b[5]) >> 1
+
+	return base*300 + ext
+}
@@ -0,0 +1,108 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pes
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// pesHeaderWithPTS is a full PES header (video stream_id 0xE0, data-aligned,
+// PTS-only) followed by 3 bytes of payload.
+const pesHeaderWithPTS = "000001e000008480052100558643aabbcc"
+
+func TestNewPESHeaderParsesPTS(t *testing.T) {
+	b, _ := hex.DecodeString(pesHeaderWithPTS)
+
+	h, err := NewPESHeader(b)
+	if err != nil {
+		t.Fatalf("NewPESHeader: %v", err)
+	}
+	if h.StreamID() != 0xE0 {
+		t.Errorf("StreamID = %#x, want 0xE0", h.StreamID())
+	}
+	if !h.DataAligned() {
+		t.Error("expected DataAligned to be true")
+	}
+	if !h.HasPTS() {
+		t.Fatal("expected HasPTS to be true")
+	}
+	if h.PTS() != 0x154321 {
+		t.Errorf("PTS = %#x, want 0x154321", h.PTS())
+	}
+	if h.HasDTS() {
+		t.Error("expected HasDTS to be false")
+	}
+	if want := []byte{0xaa, 0xbb, 0xcc}; string(h.Data()) != string(want) {
+		t.Errorf("Data = %x, want %x", h.Data(), want)
+	}
+}
+
+func TestNewPESHeaderTooShortForStartCode(t *testing.T) {
+	_, err := NewPESHeader([]byte{0x00, 0x00, 0x01, 0xE0, 0x00})
+	if err != ErrPESParse {
+		t.Errorf("NewPESHeader error = %v, want ErrPESParse", err)
+	}
+}
+
+func TestNewPESHeaderBadStartCode(t *testing.T) {
+	b, _ := hex.DecodeString(pesHeaderWithPTS)
+	b[2] = 0x02 // corrupt the start_code_prefix
+	if _, err := NewPESHeader(b); err != ErrPESParse {
+		t.Errorf("NewPESHeader error = %v, want ErrPESParse", err)
+	}
+}
+
+func TestNewPESHeaderNeedsMoreBytesBeforeOptionalFields(t *testing.T) {
+	// Cuts off right after PES_header_data_length, before any of the
+	// optional fields it claims to have.
+	b, _ := hex.DecodeString("000001e00000848005")
+	if _, err := NewPESHeader(b); err != ErrNeedsMoreBytes {
+		t.Errorf("NewPESHeader error = %v, want ErrNeedsMoreBytes", err)
+	}
+}
+
+func TestNewPESHeaderNeedsMoreBytesMidPTS(t *testing.T) {
+	// Cuts off 2 bytes into the 5-byte PTS.
+	b, _ := hex.DecodeString("000001e000008480052100")
+	if _, err := NewPESHeader(b); err != ErrNeedsMoreBytes {
+		t.Errorf("NewPESHeader error = %v, want ErrNeedsMoreBytes", err)
+	}
+}
+
+func TestNewPESHeaderStreamWithoutOptionalHeader(t *testing.T) {
+	// program_stream_map (0xBC) is never followed by the optional header.
+	b, _ := hex.DecodeString("000001bc0003010203")
+	h, err := NewPESHeader(b)
+	if err != nil {
+		t.Fatalf("NewPESHeader: %v", err)
+	}
+	if h.HasPTS() || h.HasDTS() || h.HasESCR() {
+		t.Error("program_stream_map header should have no optional timestamp fields")
+	}
+	if want := []byte{0x01, 0x02, 0x03}; string(h.Data()) != string(want) {
+		t.Errorf("Data = %x, want %x", h.Data(), want)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pes
+
+import "github.com/potterxu/gots/v2/packet"
+
+// Reassembler concatenates PES payload bytes across packets on a single PID,
+// from one payload_unit_start_indicator to the next, to yield complete PES
+// units. AlignedPUSI on its own only sees one packet at a time and so can't
+// tell when a PES unit ends, or finish parsing a header NeedsMorePackets cut
+// short; Reassembler is the layer on top that does both.
+type Reassembler struct {
+	pid int
+
+	// collecting/rawBuf hold raw bytes for a header that hasn't parsed yet
+	// (AlignedPUSI returned NeedsMorePackets).
+	collecting bool
+	rawBuf     []byte
+
+	// header/buf/pending hold the most recently parsed unit, still
+	// accumulating payload until the next PUSI completes it.
+	header  PESHeader
+	buf     []byte
+	pending bool
+}
+
+// NewReassembler creates a Reassembler that reassembles PES units carried on
+// pid.
+func NewReassembler(pid int) *Reassembler {
+	return &Reassembler{pid: pid}
+}
+
+// WritePacket feeds pkt through the reassembler. Packets on a different PID
+// are ignored. When pkt starts a new PES unit and a previous unit was
+// pending, that previous unit's header and complete data are returned -
+// even if pkt's own header turns out to be malformed or still incomplete -
+// so a bad or split packet never drops a unit that had already completed.
+// Otherwise WritePacket returns a nil header and data while it keeps
+// buffering toward the next unit boundary.
+func (r *Reassembler) WritePacket(pkt *packet.Packet) (header PESHeader, data []byte, err error) {
+	if packet.Pid(pkt) != r.pid {
+		return nil, nil, nil
+	}
+
+	if !pkt.PayloadUnitStartIndicator() {
+		payload, err := packet.Payload(pkt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r.collecting {
+			r.rawBuf = append(r.rawBuf, payload...)
+			if _, perr := r.tryParseHeader(); perr != nil {
+				return nil, nil, perr
+			}
+			return nil, nil, nil
+		}
+		if r.pending {
+			r.buf = append(r.buf, payload...)
+		}
+		return nil, nil, nil
+	}
+
+	completedHeader, completedData := r.header, r.buf
+	if !r.pending {
+		completedHeader, completedData = nil, nil
+	}
+	r.header, r.buf, r.pending = nil, nil, false
+	r.collecting, r.rawBuf = false, nil
+
+	pesHeaderBytes, err := packet.PESHeader(pkt)
+	if err != nil {
+		return completedHeader, completedData, err
+	}
+
+	r.rawBuf = append([]byte(nil), pesHeaderBytes...)
+	r.collecting = true
+	if _, perr := r.tryParseHeader(); perr != nil {
+		return completedHeader, completedData, perr
+	}
+
+	return completedHeader, completedData, nil
+}
+
+// tryParseHeader attempts to parse r.rawBuf as a PES header. If there isn't
+// enough of it yet, it leaves r.collecting set so the next packet's payload
+// is appended and parsing is retried. Once it succeeds (or fails for a
+// reason more bytes can't fix), collection stops.
+func (r *Reassembler) tryParseHeader() (PESHeader, error) {
+	header, err := NewPESHeader(r.rawBuf)
+	if err == ErrNeedsMoreBytes {
+		return nil, nil
+	}
+	r.collecting = false
+	r.rawBuf = nil
+	if err != nil {
+		return nil, err
+	}
+
+	r.header = header
+	r.buf = append([]byte(nil), header.Data()...)
+	r.pending = true
+	return header, nil
+}
+
+// Flush returns whatever PES unit is currently buffered, without waiting for
+// the next payload_unit_start_indicator. Callers should invoke it once at
+// end of stream to avoid losing the last unit.
+func (r *Reassembler) Flush() (header PESHeader, data []byte) {
+	if !r.pending {
+		return nil, nil
+	}
+	header, data = r.header, r.buf
+	r.header = nil
+	r.buf = nil
+	r.pending = false
+	return header, data
+}
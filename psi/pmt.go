@@ -39,6 +39,8 @@ const PidNotFound int = 1<<16 - 1
 const (
 	programInfoLengthOffset         = 10 // includes PSIHeaderLen
 	pmtEsDescriptorStaticLen uint16 = 5
+	programNumberOffset             = 3
+	pcrPidOffset                    = 8
 )
 
 // Unaccounted bytes before the end of the SectionLength field
@@ -58,6 +60,10 @@ type PMT interface {
 	IsPidForStreamWherePresentationLagsEbp(pid int) bool
 	String() string
 	PIDExists(pid int) bool
+	ProgramNumber() uint16
+	PCRPid() int
+	ProgramDescriptors() []PmtDescriptor
+	Marshal() ([]byte, error)
 }
 
 type pmt struct {
@@ -65,6 +71,9 @@ type pmt struct {
 	elementaryStreams    []PmtElementaryStream
 	versionNumber        uint8
 	currentNextIndicator bool
+	programNumber        uint16
+	pcrPid               int
+	programDescriptors   []PmtDescriptor
 }
 
 // PmtAccumulatorDoneFunc is a doneFunc that can be used for packet accumulation
@@ -135,9 +144,21 @@ func (p *pmt) parsePMTSection(pmtBytes []byte) error {
 		return err
 	}
 
+	p.programNumber = binary.BigEndian.Uint16(pmtBytes[programNumberOffset : programNumberOffset+2])
+	p.pcrPid = int(pmtBytes[pcrPidOffset]&0x1f)<<8 | int(pmtBytes[pcrPidOffset+1])
+
 	programInfoLength := uint16(pmtBytes[programInfoLengthOffset]&0x0f)<<8 |
 		uint16(pmtBytes[programInfoLengthOffset+1])
 
+	if programInfoLength != 0 {
+		p.programDescriptors, err = parseDescriptorLoop(pmtBytes[programInfoLengthOffset+2 : programInfoLengthOffset+2+programInfoLength])
+		if err != nil {
+			return err
+		}
+	} else {
+		p.programDescriptors = nil
+	}
+
 	// start at the stream descriptors, parse until the CRC
 	for offset := programInfoLengthOffset + 2 + programInfoLength; offset < PSIHeaderLen+sectionLength-pmtEsDescriptorStaticLen-CrcLen; {
 		elementaryStreamType := uint8(pmtBytes[offset])
@@ -250,6 +271,60 @@ func (p *pmt) PIDExists(pid int) bool {
 	return false
 }
 
+// ProgramNumber returns the program_number field of the PMT
+func (p *pmt) ProgramNumber() uint16 {
+	return p.programNumber
+}
+
+// PCRPid returns the PCR_PID field of the PMT, the PID carrying the PCR for
+// this program, as the 13-bit value parsed from the section
+func (p *pmt) PCRPid() int {
+	return p.pcrPid
+}
+
+// ProgramDescriptors returns the descriptors found in the PMT's
+// program_info loop
+func (p *pmt) ProgramDescriptors() []PmtDescriptor {
+	return p.programDescriptors
+}
+
+// Marshal serializes the PMT back into a pointer-field-prefixed PSI section,
+// the same byte shape NewPMT expects. Round-tripping NewPMT(b) followed by
+// Marshal() is lossless, so callers can parse a PMT, mutate it (for example
+// via RemoveElementaryStreams), and re-serialize it for a remuxing pipeline.
+func (p *pmt) Marshal() ([]byte, error) {
+	section, err := marshalPMTSection(p.programNumber, p.pcrPid, p.versionNumber, p.currentNextIndicator, p.programDescriptors, p.elementaryStreams)
+	if err != nil {
+		return nil, err
+	}
+	// pointer field of 0 immediately followed by the section, matching the
+	// shape parseTables/NewPMT expect.
+	return append([]byte{0x00}, section...), nil
+}
+
+// parseDescriptorLoop parses a contiguous run of tag/length/data descriptors,
+// as found in both the program_info and ES descriptor loops of a PMT section.
+func parseDescriptorLoop(b []byte) ([]PmtDescriptor, error) {
+	var descriptors []PmtDescriptor
+	var offset uint16
+	length := uint16(len(b))
+	for offset < length {
+		if offset+2 > length {
+			return nil, gots.ErrParsePMTDescriptor
+		}
+		tag := uint8(b[offset])
+		descriptorLength := uint16(b[offset+1])
+		startPos := offset + 2
+		endPos := startPos + descriptorLength
+		if endPos > length {
+			return nil, gots.ErrParsePMTDescriptor
+		}
+		descriptors = append(descriptors, NewPmtDescriptor(tag, b[startPos:endPos]))
+		offset = endPos
+	}
+	return descriptors, nil
+}
+
 func ExtractCRC(payload []byte) (uint32, error) {
 	if len(payload) < 4 {
 		return 0, gots.ErrShortPayload
@@ -0,0 +1,239 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package psi
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/potterxu/gots/v2"
+	"github.com/potterxu/gots/v2/packet"
+)
+
+// maxSectionLength is the largest section_length a PSI table may declare
+// (ISO/IEC 13818-1 2.4.4.9), which bounds how large a PMT this package can
+// build.
+const maxSectionLength = 1021
+
+// PMTBuilder synthesizes a PMT from scratch, or from a modified in-memory
+// model, and frames it into TS packets. Use NewPMTBuilder to create one,
+// configure it with the Set/Add methods, then call Build.
+type PMTBuilder struct {
+	pid                  int
+	programNumber        uint16
+	pcrPid               int
+	versionNumber        uint8
+	currentNextIndicator bool
+	programDescriptors   []PmtDescriptor
+	elementaryStreams    []PmtElementaryStream
+	nextCC               uint8
+}
+
+// NewPMTBuilder creates a PMTBuilder that will emit its packets on pid.
+func NewPMTBuilder(pid int) *PMTBuilder {
+	return &PMTBuilder{
+		pid:                  pid,
+		pcrPid:               PidNotFound,
+		currentNextIndicator: true,
+	}
+}
+
+// SetProgramNumber sets the program_number field of the PMT.
+func (b *PMTBuilder) SetProgramNumber(programNumber uint16) *PMTBuilder {
+	b.programNumber = programNumber
+	return b
+}
+
+// SetPCRPid sets the PCR_PID field of the PMT.
+func (b *PMTBuilder) SetPCRPid(pid int) *PMTBuilder {
+	b.pcrPid = pid
+	return b
+}
+
+// SetVersionNumber sets the version_number field of the PMT.
+func (b *PMTBuilder) SetVersionNumber(versionNumber uint8) *PMTBuilder {
+	b.versionNumber = versionNumber
+	return b
+}
+
+// SetContinuityCounter seeds the continuity_counter that the next Build will
+// start from. Build advances it automatically across calls, so callers
+// re-emitting an updated PMT on a live remux only need this when splicing
+// into a PID whose continuity_counter is already running (e.g. matching an
+// upstream PMT this one replaces).
+func (b *PMTBuilder) SetContinuityCounter(cc uint8) *PMTBuilder {
+	b.nextCC = cc & 0x0f
+	return b
+}
+
+// AddProgramDescriptor appends a descriptor to the PMT's program_info loop.
+func (b *PMTBuilder) AddProgramDescriptor(tag uint8, data []byte) *PMTBuilder {
+	b.programDescriptors = append(b.programDescriptors, NewPmtDescriptor(tag, data))
+	return b
+}
+
+// AddElementaryStream appends an elementary stream entry to the PMT.
+func (b *PMTBuilder) AddElementaryStream(streamType uint8, pid int, descriptors []PmtDescriptor) *PMTBuilder {
+	b.elementaryStreams = append(b.elementaryStreams, NewPmtElementaryStream(streamType, pid, descriptors))
+	return b
+}
+
+// Build serializes the configured PMT and frames it into one or more TS
+// packets on the builder's PID, padding the final packet with 0xFF. The
+// continuity_counter picks up where the previous Build call on this builder
+// left off (see SetContinuityCounter), so repeated Build calls - e.g. after
+// bumping SetVersionNumber to emit a new revision - don't look like a
+// discontinuity to a downstream SectionAssembler. Build fails with
+// gots.ErrPMTParse if SetPCRPid was never called: PCR_PID is only a 13-bit
+// field, so the PidNotFound default would otherwise be silently truncated
+// into a bogus in-range value instead of surfacing as an error.
+func (b *PMTBuilder) Build() ([]*packet.Packet, error) {
+	if b.pcrPid == PidNotFound {
+		return nil, gots.ErrPMTParse
+	}
+	section, err := marshalPMTSection(b.programNumber, b.pcrPid, b.versionNumber, b.currentNextIndicator, b.programDescriptors, b.elementaryStreams)
+	if err != nil {
+		return nil, err
+	}
+	packets, nextCC := framePSISection(b.pid, section, b.nextCC)
+	b.nextCC = nextCC
+	return packets, nil
+}
+
+// marshalPMTSection encodes a single PMT section (table_id through CRC32,
+// with no pointer field) from its constituent fields.
+func marshalPMTSection(programNumber uint16, pcrPid int, versionNumber uint8, currentNextIndicator bool, programDescriptors []PmtDescriptor, elementaryStreams []PmtElementaryStream) ([]byte, error) {
+	var body bytes.Buffer
+
+	// program_number
+	binary.Write(&body, binary.BigEndian, programNumber)
+
+	// reserved(2) | version_number(5) | current_next_indicator(1)
+	cni := uint8(0)
+	if currentNextIndicator {
+		cni = 1
+	}
+	body.WriteByte(0xc0 | versionNumber<<1 | cni)
+
+	// section_number, last_section_number: this package only ever builds a
+	// single-section PMT.
+	body.WriteByte(0x00)
+	body.WriteByte(0x00)
+
+	// reserved(3) | PCR_PID(13)
+	body.WriteByte(byte(0xe0 | (pcrPid>>8)&0x1f))
+	body.WriteByte(byte(pcrPid & 0xff))
+
+	programInfo, err := marshalDescriptorLoop(programDescriptors)
+	if err != nil {
+		return nil, err
+	}
+	if len(programInfo) > 0xfff {
+		return nil, gots.ErrPMTParse
+	}
+	body.WriteByte(byte(0xf0 | (len(programInfo)>>8)&0x0f))
+	body.WriteByte(byte(len(programInfo) & 0xff))
+	body.Write(programInfo)
+
+	for _, es := range elementaryStreams {
+		esDescriptors, err := marshalDescriptorLoop(es.ElementaryStreamDescriptors())
+		if err != nil {
+			return nil, err
+		}
+		if len(esDescriptors) > 0xfff {
+			return nil, gots.ErrPMTParse
+		}
+		body.WriteByte(byte(es.StreamType()))
+		body.WriteByte(byte(0xe0 | (es.ElementaryPid()>>8)&0x1f))
+		body.WriteByte(byte(es.ElementaryPid() & 0xff))
+		body.WriteByte(byte(0xf0 | (len(esDescriptors)>>8)&0x0f))
+		body.WriteByte(byte(len(esDescriptors) & 0xff))
+		body.Write(esDescriptors)
+	}
+
+	// section_length covers everything from here to the end of the CRC.
+	sectionLength := body.Len() + int(CrcLen)
+	if sectionLength > maxSectionLength {
+		return nil, gots.ErrPMTParse
+	}
+
+	var section bytes.Buffer
+	section.WriteByte(0x02) // table_id for a PMT
+	section.WriteByte(byte(0xb0 | (sectionLength>>8)&0x0f))
+	section.WriteByte(byte(sectionLength & 0xff))
+	section.Write(body.Bytes())
+
+	crc := gots.ComputeCRC(section.Bytes())
+	section.Write(crc)
+
+	return section.Bytes(), nil
+}
+
+// marshalDescriptorLoop encodes a run of tag/length/data descriptors.
+func marshalDescriptorLoop(descriptors []PmtDescriptor) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, d := range descriptors {
+		if len(d.Data()) > 0xff {
+			return nil, gots.ErrPMTParse
+		}
+		buf.WriteByte(d.Tag())
+		buf.WriteByte(byte(len(d.Data())))
+		buf.Write(d.Data())
+	}
+	return buf.Bytes(), nil
+}
+
+// framePSISection frames a PSI section (as produced by marshalPMTSection)
+// into TS packets on pid: a leading pointer field, the section itself, and
+// 0xFF stuffing through the end of the final packet. Packets carry
+// continuity_counter values starting from startCC; framePSISection returns
+// the next unused value so the caller (PMTBuilder.Build) can continue the
+// sequence across repeated calls instead of resetting to 0 every time.
+func framePSISection(pid int, section []byte, startCC uint8) ([]*packet.Packet, uint8) {
+	var buf bytes.Buffer
+	buf.WriteByte(0x00) // pointer field
+	buf.Write(section)
+
+	payload := buf.Bytes()
+	var packets []*packet.Packet
+	cc := startCC & 0x0f
+	for pusi := byte(0x40); packets == nil || len(payload) > 0; pusi = 0x00 {
+		var pkt packet.Packet
+		pkt[0] = 0x47
+		pkt[1] = pusi | byte(pid>>8)&0x1f
+		pkt[2] = byte(pid & 0xff)
+		pkt[3] = 0x10 | cc&0x0f // payload only, no adaptation field
+		cc = (cc + 1) & 0x0f
+
+		n := copy(pkt[4:], payload)
+		payload = payload[n:]
+		for i := 4 + n; i < packet.PacketSize; i++ {
+			pkt[i] = 0xff
+		}
+
+		packets = append(packets, &pkt)
+	}
+	return packets, cc
+}
@@ -0,0 +1,122 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package psi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/potterxu/gots/v2"
+	"github.com/potterxu/gots/v2/packet"
+)
+
+// buildPMTFromPackets reassembles the payloads of packets (all on the same
+// PID, as produced by PMTBuilder.Build) and parses them back into a PMT.
+func buildPMTFromPackets(t *testing.T, packets []*packet.Packet) PMT {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, pkt := range packets {
+		payload, err := packet.Payload(pkt)
+		if err != nil {
+			t.Fatalf("packet.Payload: %v", err)
+		}
+		buf.Write(payload)
+	}
+	pmt, err := NewPMT(buf.Bytes())
+	if err != nil {
+		t.Fatalf("NewPMT: %v", err)
+	}
+	return pmt
+}
+
+func TestPMTBuilderBuildRequiresPCRPid(t *testing.T) {
+	builder := NewPMTBuilder(0x64).
+		SetProgramNumber(7).
+		AddElementaryStream(0x1b, 0x66, nil)
+
+	if _, err := builder.Build(); err != gots.ErrPMTParse {
+		t.Errorf("Build error = %v, want gots.ErrPMTParse when PCRPid was never set", err)
+	}
+}
+
+func TestPMTBuilderBuild(t *testing.T) {
+	builder := NewPMTBuilder(0x64).
+		SetProgramNumber(7).
+		SetPCRPid(0x65).
+		SetVersionNumber(2).
+		AddElementaryStream(0x1b, 0x66, nil)
+
+	packets, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(packets) == 0 {
+		t.Fatal("Build returned no packets")
+	}
+	for _, pkt := range packets {
+		if packet.Pid(pkt) != 0x64 {
+			t.Errorf("packet PID = %#x, want 0x64", packet.Pid(pkt))
+		}
+	}
+
+	pmt := buildPMTFromPackets(t, packets)
+	if pmt.ProgramNumber() != 7 {
+		t.Errorf("ProgramNumber = %d, want 7", pmt.ProgramNumber())
+	}
+	if pmt.PCRPid() != 0x65 {
+		t.Errorf("PCRPid = %#x, want 0x65", pmt.PCRPid())
+	}
+	if pmt.VersionNumber() != 2 {
+		t.Errorf("VersionNumber = %d, want 2", pmt.VersionNumber())
+	}
+}
+
+// TestPMTBuilderContinuityCounterContinues checks that repeated Build calls
+// on the same builder (e.g. after bumping the version for a new revision)
+// continue the continuity_counter sequence instead of resetting to 0, so a
+// downstream SectionAssembler never sees a spurious discontinuity.
+func TestPMTBuilderContinuityCounterContinues(t *testing.T) {
+	builder := NewPMTBuilder(0x64).
+		SetProgramNumber(7).
+		SetPCRPid(0x65).
+		AddElementaryStream(0x1b, 0x66, nil)
+
+	first, err := builder.Build()
+	if err != nil {
+		t.Fatalf("first Build: %v", err)
+	}
+
+	builder.SetVersionNumber(1)
+	second, err := builder.Build()
+	if err != nil {
+		t.Fatalf("second Build: %v", err)
+	}
+
+	lastCC := packet.ContinuityCounter(first[len(first)-1])
+	firstCC := packet.ContinuityCounter(second[0])
+	if want := (lastCC + 1) & 0x0f; firstCC != want {
+		t.Errorf("second Build's first continuity_counter = %d, want %d (continuing from %d)", firstCC, want, lastCC)
+	}
+}
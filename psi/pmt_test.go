@@ -0,0 +1,74 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package psi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// TestPMTMarshalRoundTrip parses a hand-built PMT section (program_number 1,
+// PCR_PID 0x65, one elementary stream of type 0x1b on PID 0x66), re-marshals
+// it, and checks that both the bytes and the re-parsed fields come back
+// unchanged.
+func TestPMTMarshalRoundTrip(t *testing.T) {
+	b, _ := hex.DecodeString("0002b0120001c30000e065f0001be066f000ada7a58f")
+
+	parsed, err := NewPMT(b)
+	if err != nil {
+		t.Fatalf("NewPMT: %v", err)
+	}
+	if parsed.ProgramNumber() != 1 {
+		t.Errorf("ProgramNumber = %d, want 1", parsed.ProgramNumber())
+	}
+	if parsed.PCRPid() != 0x65 {
+		t.Errorf("PCRPid = %#x, want 0x65", parsed.PCRPid())
+	}
+	if parsed.VersionNumber() != 1 {
+		t.Errorf("VersionNumber = %d, want 1", parsed.VersionNumber())
+	}
+	if len(parsed.ElementaryStreams()) != 1 || parsed.ElementaryStreams()[0].ElementaryPid() != 0x66 {
+		t.Fatalf("unexpected elementary streams: %v", parsed.ElementaryStreams())
+	}
+
+	marshaled, err := parsed.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Equal(marshaled, b) {
+		t.Errorf("Marshal = %x, want %x", marshaled, b)
+	}
+
+	reparsed, err := NewPMT(marshaled)
+	if err != nil {
+		t.Fatalf("NewPMT(marshaled): %v", err)
+	}
+	if reparsed.ProgramNumber() != parsed.ProgramNumber() ||
+		reparsed.PCRPid() != parsed.PCRPid() ||
+		reparsed.VersionNumber() != parsed.VersionNumber() {
+		t.Errorf("reparsed PMT fields do not match original: %+v vs %+v", reparsed, parsed)
+	}
+}
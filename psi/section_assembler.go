@@ -0,0 +1,329 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package psi
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/potterxu/gots/v2"
+	"github.com/potterxu/gots/v2/packet"
+)
+
+const (
+	tableIDPAT uint8 = 0x00
+	tableIDCAT uint8 = 0x01
+	tableIDPMT uint8 = 0x02
+)
+
+var (
+	// ErrDuplicatePacket is returned by SectionAssembler.WritePacket when a
+	// packet repeats the continuity_counter of the previous packet on its
+	// PID; its payload was already processed and is not reprocessed.
+	ErrDuplicatePacket = errors.New("psi: duplicate packet")
+	// ErrDiscontinuity is returned by SectionAssembler.WritePacket when a
+	// packet's continuity_counter skips ahead, indicating lost packets; any
+	// section that was being assembled is discarded.
+	ErrDiscontinuity = errors.New("psi: continuity counter discontinuity")
+)
+
+// sectionsChanDepth is how many pending sections NextSection/Sections will
+// buffer; once it is full, the oldest queued section is dropped to make room
+// for a newer one.
+const sectionsChanDepth = 4
+
+// SectionAssembler demuxes PSI sections carried on a single PID across many
+// packets. Unlike packet.Accumulator, it is not a one-shot: it keeps running
+// across a live stream, validating the continuity counter, splitting and
+// reassembling sections at PUSI/pointer-field boundaries, discarding 0xFF
+// stuffing, and delivering only sections whose version_number or
+// current_next_indicator actually changed.
+type SectionAssembler struct {
+	pid           int
+	tableID       uint8 // expected table_id, only meaningful if filterTableID is set
+	filterTableID bool  // false means accept sections of any table_id
+	lastCC        int8  // -1 until the first packet on pid is seen
+	buf           []byte
+	inSection     bool
+
+	haveVersion bool
+	lastVersion uint8
+	lastCNI     bool
+
+	sections chan []byte
+}
+
+// newSectionAssembler builds an assembler for pid that only dispatches
+// sections whose table_id matches tableID.
+func newSectionAssembler(pid int, tableID uint8) *SectionAssembler {
+	return &SectionAssembler{
+		pid:           pid,
+		tableID:       tableID,
+		filterTableID: true,
+		lastCC:        -1,
+		sections:      make(chan []byte, sectionsChanDepth),
+	}
+}
+
+// NewSectionAssembler creates a SectionAssembler for pid that dispatches any
+// PSI section it encounters on that PID, regardless of table_id.
+func NewSectionAssembler(pid int) *SectionAssembler {
+	return &SectionAssembler{
+		pid:      pid,
+		lastCC:   -1,
+		sections: make(chan []byte, sectionsChanDepth),
+	}
+}
+
+// WritePacket feeds pkt through the assembler. Packets on a different PID
+// are ignored. Completed, CRC-valid sections with a new version_number or
+// current_next_indicator are queued for NextSection/Sections. A duplicate
+// packet is dropped and reported with ErrDuplicatePacket; a discontinuity is
+// reported with ErrDiscontinuity after the packet's own payload is processed.
+// Stuffing, sections with a bad CRC or another table_id, and unchanged
+// versions are dropped silently.
+func (a *SectionAssembler) WritePacket(pkt *packet.Packet) error {
+	if packet.Pid(pkt) != a.pid {
+		return nil
+	}
+
+	// A duplicate retransmission carries a payload we've already processed,
+	// so skip it entirely. A discontinuity, on the other hand, only means
+	// the stale in-progress section (already discarded by checkContinuity)
+	// can't be trusted - this packet's own payload may still start a brand
+	// new, perfectly valid section via PUSI, so keep processing it.
+	ccErr := a.checkContinuity(pkt)
+	if ccErr == ErrDuplicatePacket {
+		return ccErr
+	}
+
+	payload, err := packet.Payload(pkt)
+	if err != nil {
+		return err
+	}
+	if len(payload) == 0 {
+		return ccErr
+	}
+
+	if pkt.PayloadUnitStartIndicator() {
+		pointer := int(payload[0])
+		if 1+pointer > len(payload) {
+			return gots.ErrPMTParse
+		}
+		// The bytes up to the pointer finish whatever section was already
+		// in progress.
+		if a.inSection {
+			a.buf = append(a.buf, payload[1:1+pointer]...)
+		}
+		a.drain()
+
+		a.buf = nil
+		a.inSection = true
+		a.buf = append(a.buf, payload[1+pointer:]...)
+	} else if a.inSection {
+		a.buf = append(a.buf, payload...)
+	} else {
+		// No section in progress and no PUSI: nothing to reassemble yet.
+		return ccErr
+	}
+
+	a.drain()
+	return ccErr
+}
+
+// checkContinuity validates pkt's continuity_counter, dropping duplicate
+// retransmissions and flagging (by discarding the in-progress section)
+// discontinuities so a dropped packet can't silently corrupt a section.
+func (a *SectionAssembler) checkContinuity(pkt *packet.Packet) error {
+	cc := int8(packet.ContinuityCounter(pkt))
+	if a.lastCC < 0 {
+		a.lastCC = cc
+		return nil
+	}
+
+	if cc == a.lastCC {
+		// Duplicate packet (allowed by the spec to be repeated verbatim);
+		// the payload was already processed once.
+		return ErrDuplicatePacket
+	}
+
+	expected := (a.lastCC + 1) & 0x0f
+	a.lastCC = cc
+	if cc != expected {
+		// A packet was lost: whatever section we were assembling is now
+		// corrupt, so throw away the partial buffer and wait for the next
+		// PUSI to resync.
+		a.buf = nil
+		a.inSection = false
+		return ErrDiscontinuity
+	}
+	return nil
+}
+
+// drain extracts every complete, valid section currently sitting in the
+// buffer and queues the ones worth delivering.
+func (a *SectionAssembler) drain() {
+	for len(a.buf) > 2 && a.buf[0] != 0xFF {
+		length := sectionLength(a.buf)
+		total := int(length) + 3
+		if len(a.buf) < total {
+			// Section isn't fully buffered yet; wait for more packets.
+			return
+		}
+
+		section := a.buf[:total]
+		a.buf = a.buf[total:]
+		a.dispatch(section)
+	}
+
+	if len(a.buf) > 0 && a.buf[0] == 0xFF {
+		// Stuffing runs to the end of the TS packet payload.
+		a.buf = nil
+		a.inSection = false
+	}
+}
+
+// dispatch validates section's CRC and table_id and, if its version
+// actually changed, queues it for delivery.
+func (a *SectionAssembler) dispatch(section []byte) {
+	if a.filterTableID && tableID(section) != a.tableID {
+		return
+	}
+
+	if !validSectionCRC(section) {
+		return
+	}
+
+	version, cni, err := tableVersionAndCNI(section)
+	if err != nil {
+		return
+	}
+
+	if a.haveVersion && version == a.lastVersion && cni == a.lastCNI {
+		return
+	}
+	a.haveVersion = true
+	a.lastVersion = version
+	a.lastCNI = cni
+
+	select {
+	case a.sections <- section:
+	default:
+		// A slow consumer: drop the oldest queued section in favor of this
+		// newer one rather than blocking the demux loop.
+		select {
+		case <-a.sections:
+		default:
+		}
+		a.sections <- section
+	}
+}
+
+// NextSection returns the next new-version section seen on this PID, or nil
+// with a nil error if none is queued yet.
+func (a *SectionAssembler) NextSection() ([]byte, error) {
+	select {
+	case section := <-a.sections:
+		return section, nil
+	default:
+		return nil, nil
+	}
+}
+
+// Sections returns a channel of new-version sections, for callers that
+// prefer to select on it alongside other event sources.
+func (a *SectionAssembler) Sections() <-chan []byte {
+	return a.sections
+}
+
+// validSectionCRC recomputes section's CRC32 and compares it to the CRC
+// carried in its last 4 bytes.
+func validSectionCRC(section []byte) bool {
+	if len(section) < int(CrcLen) {
+		return false
+	}
+	body, crc := section[:len(section)-int(CrcLen)], section[len(section)-int(CrcLen):]
+	return bytes.Equal(gots.ComputeCRC(body), crc)
+}
+
+// PATAssembler is a SectionAssembler specialized to yield parsed PATs.
+type PATAssembler struct {
+	*SectionAssembler
+}
+
+// NewPATAssembler creates an assembler for the PAT PID.
+func NewPATAssembler() *PATAssembler {
+	return &PATAssembler{newSectionAssembler(PatPid, tableIDPAT)}
+}
+
+// NextPAT returns the next new-version PAT, or nil with a nil error if none
+// is queued yet.
+func (a *PATAssembler) NextPAT() (PAT, error) {
+	section, err := a.NextSection()
+	if err != nil || section == nil {
+		return nil, err
+	}
+	return NewPAT(append([]byte{0x00}, section...))
+}
+
+// PMTAssembler is a SectionAssembler specialized to yield parsed PMTs.
+type PMTAssembler struct {
+	*SectionAssembler
+}
+
+// NewPMTAssembler creates an assembler for the PMT carried on pid.
+func NewPMTAssembler(pid int) *PMTAssembler {
+	return &PMTAssembler{newSectionAssembler(pid, tableIDPMT)}
+}
+
+// NextPMT returns the next new-version PMT, or nil with a nil error if none
+// is queued yet.
+func (a *PMTAssembler) NextPMT() (PMT, error) {
+	section, err := a.NextSection()
+	if err != nil || section == nil {
+		return nil, err
+	}
+	return NewPMT(append([]byte{0x00}, section...))
+}
+
+// CATAssembler is a SectionAssembler specialized to yield parsed CATs.
+type CATAssembler struct {
+	*SectionAssembler
+}
+
+// NewCATAssembler creates an assembler for the CAT PID.
+func NewCATAssembler() *CATAssembler {
+	return &CATAssembler{newSectionAssembler(CatPid, tableIDCAT)}
+}
+
+// NextCAT returns the next new-version CAT, or nil with a nil error if none
+// is queued yet.
+func (a *CATAssembler) NextCAT() (CAT, error) {
+	section, err := a.NextSection()
+	if err != nil || section == nil {
+		return nil, err
+	}
+	return NewCAT(append([]byte{0x00}, section...))
+}
@@ -0,0 +1,148 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package psi
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/potterxu/gots/v2/packet"
+)
+
+// pmtSectionV1 and pmtSectionV2 are pointer-field-prefixed PMT sections
+// (program_number 1, PCR_PID 0x65, one ES on PID 0x66) differing only in
+// version_number, for exercising SectionAssembler's version-change dispatch.
+const (
+	pmtSectionV1 = "0002b0120001c30000e065f0001be066f0008e9a08fa"
+	pmtSectionV2 = "0002b0120001c50000e065f0001be066f000cf520c5a"
+)
+
+// buildTestPacket builds a single, unfragmented TS packet on pid carrying
+// payload (which must fit in one packet's 184-byte payload), stuffed with
+// 0xFF.
+func buildTestPacket(pid int, pusi bool, cc uint8, payload []byte) *packet.Packet {
+	var pkt packet.Packet
+	pusiBit := byte(0x00)
+	if pusi {
+		pusiBit = 0x40
+	}
+	pkt[0] = 0x47
+	pkt[1] = pusiBit | byte(pid>>8)&0x1f
+	pkt[2] = byte(pid & 0xff)
+	pkt[3] = 0x10 | cc&0x0f
+	n := copy(pkt[4:], payload)
+	for i := 4 + n; i < packet.PacketSize; i++ {
+		pkt[i] = 0xff
+	}
+	return &pkt
+}
+
+func TestSectionAssemblerDiscontinuityStillProcessesCurrentPacket(t *testing.T) {
+	const pid = 0x64
+	v1, _ := hex.DecodeString(pmtSectionV1)
+	v2, _ := hex.DecodeString(pmtSectionV2)
+
+	a := NewSectionAssembler(pid)
+
+	if err := a.WritePacket(buildTestPacket(pid, true, 0, v1)); err != nil {
+		t.Fatalf("first WritePacket: %v", err)
+	}
+	if section, err := a.NextSection(); err != nil || section == nil {
+		t.Fatalf("expected first section to be dispatched, got section=%v err=%v", section, err)
+	}
+
+	// cc jumps from 0 to 3 (expected 1): a discontinuity. The packet still
+	// carries a PUSI and a brand-new section, which must not be discarded
+	// along with the (nonexistent) stale partial section.
+	err := a.WritePacket(buildTestPacket(pid, true, 3, v2))
+	if err != ErrDiscontinuity {
+		t.Fatalf("WritePacket error = %v, want ErrDiscontinuity", err)
+	}
+	section, serr := a.NextSection()
+	if serr != nil || section == nil {
+		t.Fatalf("expected the post-discontinuity section to still be dispatched, got section=%v err=%v", section, serr)
+	}
+}
+
+func TestSectionAssemblerDuplicatePacketIgnored(t *testing.T) {
+	const pid = 0x64
+	v1, _ := hex.DecodeString(pmtSectionV1)
+
+	a := NewSectionAssembler(pid)
+	if err := a.WritePacket(buildTestPacket(pid, true, 0, v1)); err != nil {
+		t.Fatalf("first WritePacket: %v", err)
+	}
+	if section, _ := a.NextSection(); section == nil {
+		t.Fatal("expected first section to be dispatched")
+	}
+
+	// A repeated cc is a duplicate retransmission; it must not be
+	// reprocessed or re-queued.
+	err := a.WritePacket(buildTestPacket(pid, true, 0, v1))
+	if err != ErrDuplicatePacket {
+		t.Fatalf("WritePacket error = %v, want ErrDuplicatePacket", err)
+	}
+	if section, _ := a.NextSection(); section != nil {
+		t.Errorf("expected no section queued from a duplicate packet, got %x", section)
+	}
+}
+
+func TestSectionAssemblerSkipsUnchangedVersion(t *testing.T) {
+	const pid = 0x64
+	v1, _ := hex.DecodeString(pmtSectionV1)
+
+	a := NewSectionAssembler(pid)
+	if err := a.WritePacket(buildTestPacket(pid, true, 0, v1)); err != nil {
+		t.Fatalf("first WritePacket: %v", err)
+	}
+	if section, _ := a.NextSection(); section == nil {
+		t.Fatal("expected first section to be dispatched")
+	}
+
+	// Same version_number and current_next_indicator: nothing new to
+	// deliver, even though this is a distinct, well-formed packet.
+	if err := a.WritePacket(buildTestPacket(pid, true, 1, v1)); err != nil {
+		t.Fatalf("second WritePacket: %v", err)
+	}
+	if section, _ := a.NextSection(); section != nil {
+		t.Errorf("expected no section queued for an unchanged version, got %x", section)
+	}
+}
+
+// TestPATAssemblerFiltersByTableID checks that a PATAssembler, whose
+// table_id (0x00) is also the zero value of the tableID field, still
+// rejects a non-PAT section (e.g. a PMT, table_id 0x02).
+func TestPATAssemblerFiltersByTableID(t *testing.T) {
+	const pid = PatPid
+	v1, _ := hex.DecodeString(pmtSectionV1)
+
+	a := NewPATAssembler()
+	if err := a.WritePacket(buildTestPacket(pid, true, 0, v1)); err != nil {
+		t.Fatalf("WritePacket: %v", err)
+	}
+	if section, _ := a.NextSection(); section != nil {
+		t.Errorf("expected a PMT section to be filtered out by PATAssembler, got %x", section)
+	}
+}
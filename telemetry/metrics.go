@@ -0,0 +1,154 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package telemetry
+
+import (
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+const (
+	metricDiscontinuities  = "ts.continuity.discontinuities"
+	metricScrambled        = "ts.scrambled.packets"
+	metricPCRInterval      = "ts.pcr.interval.ms"
+	metricPCRJitter        = "ts.pcr.jitter.ms"
+	metricPMTVersion       = "ts.pmt.version"
+	metricPATVersion       = "ts.pat.version"
+	metricPESAlignFailures = "ts.pes.alignment.failures"
+	metricBitrate          = "ts.bitrate.bps"
+
+	pidAttrKey = "pid"
+)
+
+// buildMetrics assembles an OTLP ExportMetricsServiceRequest from the
+// per-PID stats: cumulative counters since the observer started, and
+// windowed values (bytes, PCR samples) since the last flush.
+func buildMetrics(resourceAttrs map[string]string, patVersion int, pids map[int]*pidStats, elapsed time.Duration) *colmetricspb.ExportMetricsServiceRequest {
+	now := uint64(time.Now().UnixNano())
+
+	var metrics []*metricspb.Metric
+	if patVersion >= 0 {
+		metrics = append(metrics, gauge(metricPATVersion, nil, float64(patVersion), now))
+	}
+
+	for pid, stats := range pids {
+		attrs := []*commonpb.KeyValue{intAttr(pidAttrKey, int64(pid))}
+
+		metrics = append(metrics,
+			sum(metricDiscontinuities, attrs, float64(stats.discontinuities), now),
+			sum(metricScrambled, attrs, float64(stats.scrambledCount), now),
+			sum(metricPESAlignFailures, attrs, float64(stats.pesAlignFailures), now),
+			gauge(metricBitrate, attrs, bitrate(stats.byteCount, elapsed), now),
+		)
+
+		if stats.pmtVersion >= 0 {
+			metrics = append(metrics, gauge(metricPMTVersion, attrs, float64(stats.pmtVersion), now))
+		}
+		if stats.pcrSampleCount > 0 {
+			avgInterval := stats.pcrIntervalSum / time.Duration(stats.pcrSampleCount)
+			avgJitter := stats.pcrJitterSum / time.Duration(stats.pcrSampleCount)
+			metrics = append(metrics,
+				gauge(metricPCRInterval, attrs, float64(avgInterval.Milliseconds()), now),
+				gauge(metricPCRJitter, attrs, float64(avgJitter.Milliseconds()), now),
+			)
+		}
+	}
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{
+				Resource: &resourcepb.Resource{Attributes: mapAttrs(resourceAttrs)},
+				ScopeMetrics: []*metricspb.ScopeMetrics{
+					{Metrics: metrics},
+				},
+			},
+		},
+	}
+}
+
+func bitrate(bytes uint64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes*8) / elapsed.Seconds()
+}
+
+func gauge(name string, attrs []*commonpb.KeyValue, value float64, timeUnixNano uint64) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Gauge{
+			Gauge: &metricspb.Gauge{
+				DataPoints: []*metricspb.NumberDataPoint{
+					{
+						Attributes:   attrs,
+						TimeUnixNano: timeUnixNano,
+						Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+					},
+				},
+			},
+		},
+	}
+}
+
+func sum(name string, attrs []*commonpb.KeyValue, value float64, timeUnixNano uint64) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Sum{
+			Sum: &metricspb.Sum{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+				DataPoints: []*metricspb.NumberDataPoint{
+					{
+						Attributes:   attrs,
+						TimeUnixNano: timeUnixNano,
+						Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+					},
+				},
+			},
+		},
+	}
+}
+
+func intAttr(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}},
+	}
+}
+
+func mapAttrs(attrs map[string]string) []*commonpb.KeyValue {
+	var kvs []*commonpb.KeyValue
+	for k, v := range attrs {
+		kvs = append(kvs, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+		})
+	}
+	return kvs
+}
@@ -0,0 +1,69 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package telemetry
+
+import (
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// defaultFlushInterval is how often Observe exports a batch of metrics when
+// the caller hasn't configured WithFlushInterval.
+const defaultFlushInterval = 10 * time.Second
+
+type config struct {
+	flushInterval  time.Duration
+	resourceAttrs  map[string]string
+	headers        map[string]string
+	compression    Compression
+	exportTimeout  time.Duration
+	transportCreds credentials.TransportCredentials // nil means NewGRPCExporter dials plaintext
+}
+
+func defaultConfig() config {
+	return config{
+		flushInterval: defaultFlushInterval,
+		resourceAttrs: map[string]string{},
+	}
+}
+
+// Option configures a TSObserver or Exporter.
+type Option func(*config)
+
+// WithFlushInterval overrides how often Observe exports a batch of metrics.
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.flushInterval = d
+	}
+}
+
+// WithResourceAttribute attaches a resource-level attribute (e.g. stream
+// name) to every metric this observer exports.
+func WithResourceAttribute(key, value string) Option {
+	return func(c *config) {
+		c.resourceAttrs[key] = value
+	}
+}
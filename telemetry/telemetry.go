@@ -0,0 +1,262 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package telemetry instruments a TS packet processing pipeline and reports
+// stream health metrics over OTLP, so operators get production monitoring
+// without writing custom accumulators around every PID.
+//
+// This package is the first in the module to need a gRPC/OTLP client, so it
+// pulls in dependencies the rest of the module doesn't otherwise require:
+// go.opentelemetry.io/proto/otlp, google.golang.org/grpc, and
+// google.golang.org/grpc/credentials. They need to land in the module's
+// go.mod/go.sum (run `go get` for each, then `go mod tidy`) before this
+// package will build as part of the module.
+package telemetry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/potterxu/gots/v2"
+	"github.com/potterxu/gots/v2/packet"
+	"github.com/potterxu/gots/v2/pes"
+	"github.com/potterxu/gots/v2/psi"
+)
+
+// TSObserver watches packets as they pass through a caller's read loop and
+// periodically exports accumulated health metrics through an Exporter.
+type TSObserver struct {
+	exporter      Exporter
+	flushInterval time.Duration
+	resourceAttrs map[string]string
+
+	mu        sync.Mutex
+	pids      map[int]*pidStats
+	patAcc    *packet.Accumulator
+	patVer    int          // -1 until first PAT is seen
+	pmtPids   map[int]bool // learned from the PAT; nil until the first PAT is seen
+	lastFlush time.Time
+}
+
+// pidStats holds the running counters for a single PID between flushes.
+type pidStats struct {
+	lastCC           int8 // -1 until the first packet for this PID is seen
+	discontinuities  uint64
+	scrambledCount   uint64
+	packetCount      uint64
+	byteCount        uint64
+	lastPCR          uint64
+	lastPCRSeen      bool
+	pcrIntervalSum   time.Duration
+	pcrJitterSum     time.Duration
+	pcrSampleCount   uint64
+	pesAlignFailures uint64
+
+	pmtAcc     *packet.Accumulator
+	pmtVersion int // -1 until a PMT version has been observed
+}
+
+// NewTSObserver creates a TSObserver that exports through exporter. opts
+// configure the flush cadence and the resource attributes attached to every
+// exported metric (e.g. stream name, source IP).
+func NewTSObserver(exporter Exporter, opts ...Option) *TSObserver {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &TSObserver{
+		exporter:      exporter,
+		flushInterval: cfg.flushInterval,
+		resourceAttrs: cfg.resourceAttrs,
+		pids:          make(map[int]*pidStats),
+		patVer:        -1,
+		lastFlush:     time.Now(),
+	}
+}
+
+// Observe updates the observer's running counters for pkt and, if the
+// configured flush interval has elapsed, exports a batch of metrics. Callers
+// feed every packet of a stream through Observe in their read loop.
+func (o *TSObserver) Observe(pkt *packet.Packet) error {
+	o.mu.Lock()
+	o.observeLocked(pkt)
+	due := time.Since(o.lastFlush) >= o.flushInterval
+	o.mu.Unlock()
+
+	if due {
+		return o.Flush()
+	}
+	return nil
+}
+
+func (o *TSObserver) observeLocked(pkt *packet.Packet) {
+	pid := packet.Pid(pkt)
+	stats, ok := o.pids[pid]
+	if !ok {
+		stats = &pidStats{lastCC: -1, pmtVersion: -1}
+		o.pids[pid] = stats
+	}
+
+	stats.packetCount++
+	stats.byteCount += packet.PacketSize
+
+	o.observeContinuity(stats, pkt)
+	o.observeScrambling(stats, pkt)
+	o.observePCR(stats, pkt)
+
+	if pid == psi.PatPid {
+		o.observePAT(pkt)
+	} else if o.pmtPids[pid] {
+		o.observePMT(pid, stats, pkt)
+	}
+
+	_, _, status, _ := pes.AlignedPUSI(pkt)
+	if status == pes.PUSIButNotAligned {
+		stats.pesAlignFailures++
+	}
+}
+
+func (o *TSObserver) observeContinuity(stats *pidStats, pkt *packet.Packet) {
+	cc := int8(packet.ContinuityCounter(pkt))
+	if stats.lastCC >= 0 {
+		expected := (stats.lastCC + 1) & 0x0f
+		if cc != expected {
+			stats.discontinuities++
+		}
+	}
+	stats.lastCC = cc
+}
+
+func (o *TSObserver) observeScrambling(stats *pidStats, pkt *packet.Packet) {
+	if packet.TransportScramblingControl(pkt) != 0 {
+		stats.scrambledCount++
+	}
+}
+
+func (o *TSObserver) observePCR(stats *pidStats, pkt *packet.Packet) {
+	if !packet.ContainsPcr(pkt) {
+		return
+	}
+	pcr, err := packet.PCR(pkt)
+	if err != nil {
+		return
+	}
+	if stats.lastPCRSeen {
+		interval := pcrDelta(stats.lastPCR, pcr)
+		stats.pcrIntervalSum += interval
+		if stats.pcrSampleCount > 0 {
+			mean := stats.pcrIntervalSum / time.Duration(stats.pcrSampleCount+1)
+			jitter := interval - mean
+			if jitter < 0 {
+				jitter = -jitter
+			}
+			stats.pcrJitterSum += jitter
+		}
+		stats.pcrSampleCount++
+	}
+	stats.lastPCR = pcr
+	stats.lastPCRSeen = true
+}
+
+// pcrDelta converts the 27MHz PCR difference between two samples into a
+// time.Duration, assuming pcr does not wrap more than once between samples.
+func pcrDelta(prev, curr uint64) time.Duration {
+	const pcrWrap = uint64(1) << 33 * 300
+	delta := curr - prev
+	if curr < prev {
+		delta = pcrWrap - prev + curr
+	}
+	return time.Duration(delta * uint64(time.Second) / 27000000)
+}
+
+func (o *TSObserver) observePMT(pid int, stats *pidStats, pkt *packet.Packet) {
+	if stats.pmtAcc == nil {
+		stats.pmtAcc = packet.NewAccumulator(psi.PmtAccumulatorDoneFunc)
+	}
+
+	_, err := stats.pmtAcc.WritePacket(pkt)
+	if err == gots.ErrAccumulatorDone {
+		pmt, perr := psi.NewPMT(stats.pmtAcc.Bytes())
+		stats.pmtAcc = packet.NewAccumulator(psi.PmtAccumulatorDoneFunc)
+		if perr != nil {
+			return
+		}
+		version := int(pmt.VersionNumber())
+		if stats.pmtVersion != version {
+			stats.pmtVersion = version
+		}
+	}
+}
+
+// Flush exports the current metric values immediately, regardless of the
+// configured flush interval, and resets the windowed gauges (bitrate, PCR
+// jitter). Cumulative counters (discontinuities, scrambled packets, PES
+// alignment failures) are never reset, matching OTLP counter semantics.
+func (o *TSObserver) Flush() error {
+	o.mu.Lock()
+	now := time.Now()
+	elapsed := now.Sub(o.lastFlush)
+	batch := buildMetrics(o.resourceAttrs, o.patVer, o.pids, elapsed)
+	for _, stats := range o.pids {
+		stats.byteCount = 0
+		stats.pcrIntervalSum = 0
+		stats.pcrJitterSum = 0
+		stats.pcrSampleCount = 0
+	}
+	o.lastFlush = now
+	o.mu.Unlock()
+
+	return o.exporter.Export(batch)
+}
+
+// Close flushes any remaining metrics and releases the underlying Exporter.
+func (o *TSObserver) Close() error {
+	if err := o.Flush(); err != nil {
+		return err
+	}
+	return o.exporter.Close()
+}
+
+func (o *TSObserver) observePAT(pkt *packet.Packet) {
+	if o.patAcc == nil {
+		o.patAcc = packet.NewAccumulator(psi.PatAccumulatorDoneFunc)
+	}
+
+	_, err := o.patAcc.WritePacket(pkt)
+	if err == gots.ErrAccumulatorDone {
+		pat, perr := psi.NewPAT(o.patAcc.Bytes())
+		o.patAcc = packet.NewAccumulator(psi.PatAccumulatorDoneFunc)
+		if perr != nil {
+			return
+		}
+		o.patVer = int(pat.VersionNumber())
+
+		pmtPids := make(map[int]bool, len(pat.ProgramMap()))
+		for _, pmtPid := range pat.ProgramMap() {
+			pmtPids[pmtPid] = true
+		}
+		o.pmtPids = pmtPids
+	}
+}
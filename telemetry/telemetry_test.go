@@ -0,0 +1,215 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package telemetry
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"github.com/potterxu/gots/v2/packet"
+	"github.com/potterxu/gots/v2/psi"
+)
+
+// patMappingPMTOnPid0x64 is a pointer-field-prefixed PAT section mapping
+// program_number 1 to PMT PID 0x64.
+const patMappingPMTOnPid0x64 = "0000b00d0001c100000001e064fba6cb2a"
+
+// pmtSectionV1OnPid0x64 is a pointer-field-prefixed PMT section
+// (program_number 1, PCR_PID 0x65, one ES on PID 0x66, version_number 1).
+const pmtSectionV1OnPid0x64 = "0002b0120001c30000e065f0001be066f000ada7a58f"
+
+// fakeExporter records every batch passed to Export for inspection.
+type fakeExporter struct {
+	batches []*colmetricspb.ExportMetricsServiceRequest
+	closed  bool
+}
+
+func (f *fakeExporter) Export(req *colmetricspb.ExportMetricsServiceRequest) error {
+	f.batches = append(f.batches, req)
+	return nil
+}
+
+func (f *fakeExporter) Close() error {
+	f.closed = true
+	return nil
+}
+
+// lastMetrics returns the Metric slice of the most recently exported batch.
+func (f *fakeExporter) lastMetrics(t *testing.T) []*metricspb.Metric {
+	t.Helper()
+	if len(f.batches) == 0 {
+		t.Fatal("no batch was exported")
+	}
+	batch := f.batches[len(f.batches)-1]
+	return batch.ResourceMetrics[0].ScopeMetrics[0].Metrics
+}
+
+// metricValueForPid returns the single data point value of the named metric
+// whose "pid" attribute matches pid.
+func metricValueForPid(t *testing.T, metrics []*metricspb.Metric, name string, pid int64) float64 {
+	t.Helper()
+	for _, m := range metrics {
+		if m.Name != name {
+			continue
+		}
+		var dps []*metricspb.NumberDataPoint
+		switch d := m.Data.(type) {
+		case *metricspb.Metric_Sum:
+			dps = d.Sum.DataPoints
+		case *metricspb.Metric_Gauge:
+			dps = d.Gauge.DataPoints
+		}
+		for _, dp := range dps {
+			for _, attr := range dp.Attributes {
+				if attr.Key == pidAttrKey && attr.GetValue().GetIntValue() == pid {
+					return dp.GetAsDouble()
+				}
+			}
+		}
+	}
+	t.Fatalf("metric %q for pid %#x not found", name, pid)
+	return 0
+}
+
+func buildTestPacket(pid int, pusi bool, cc uint8, payload []byte) *packet.Packet {
+	var pkt packet.Packet
+	pusiBit := byte(0x00)
+	if pusi {
+		pusiBit = 0x40
+	}
+	pkt[0] = 0x47
+	pkt[1] = pusiBit | byte(pid>>8)&0x1f
+	pkt[2] = byte(pid & 0xff)
+	pkt[3] = 0x10 | cc&0x0f
+	n := copy(pkt[4:], payload)
+	for i := 4 + n; i < packet.PacketSize; i++ {
+		pkt[i] = 0xff
+	}
+	return &pkt
+}
+
+func TestObserveCountsDiscontinuities(t *testing.T) {
+	const pid = 0x100
+	exp := &fakeExporter{}
+	o := NewTSObserver(exp)
+
+	if err := o.Observe(buildTestPacket(pid, false, 0, nil)); err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	if err := o.Observe(buildTestPacket(pid, false, 1, nil)); err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	// cc jumps from 1 to 3 (expected 2): one discontinuity.
+	if err := o.Observe(buildTestPacket(pid, false, 3, nil)); err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+
+	if err := o.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	got := metricValueForPid(t, exp.lastMetrics(t), metricDiscontinuities, pid)
+	if got != 1 {
+		t.Errorf("discontinuities = %v, want 1", got)
+	}
+}
+
+func TestObservePATGatesPMTAccumulation(t *testing.T) {
+	const pmtPid = 0x64
+	const otherPid = 0x65 // not a PMT PID per the PAT below
+	exp := &fakeExporter{}
+	o := NewTSObserver(exp)
+
+	pat, _ := hex.DecodeString(patMappingPMTOnPid0x64)
+	if err := o.Observe(buildTestPacket(psi.PatPid, true, 0, pat)); err != nil {
+		t.Fatalf("Observe(PAT): %v", err)
+	}
+
+	// Arbitrary ES payload on a PID the PAT did not map to a PMT: must never
+	// start PMT accumulation (the bug 0bb70cc fixed).
+	if err := o.Observe(buildTestPacket(otherPid, true, 0, []byte{0x01, 0x02, 0x03, 0x04})); err != nil {
+		t.Fatalf("Observe(other pid): %v", err)
+	}
+	o.mu.Lock()
+	otherStats := o.pids[otherPid]
+	o.mu.Unlock()
+	if otherStats.pmtAcc != nil {
+		t.Error("expected no PMT accumulation on a PID the PAT didn't map to a PMT")
+	}
+
+	pmt, _ := hex.DecodeString(pmtSectionV1OnPid0x64)
+	if err := o.Observe(buildTestPacket(pmtPid, true, 0, pmt)); err != nil {
+		t.Fatalf("Observe(PMT): %v", err)
+	}
+	o.mu.Lock()
+	pmtStats := o.pids[pmtPid]
+	o.mu.Unlock()
+	if pmtStats.pmtVersion != 1 {
+		t.Errorf("pmtVersion = %d, want 1", pmtStats.pmtVersion)
+	}
+}
+
+func TestFlushBitrateRoundTrip(t *testing.T) {
+	const pid = 0x200
+	exp := &fakeExporter{}
+	o := NewTSObserver(exp)
+
+	const packetCount = 10
+	for i := 0; i < packetCount; i++ {
+		if err := o.Observe(buildTestPacket(pid, false, uint8(i), nil)); err != nil {
+			t.Fatalf("Observe: %v", err)
+		}
+	}
+
+	o.mu.Lock()
+	o.lastFlush = time.Now().Add(-1 * time.Second)
+	o.mu.Unlock()
+
+	if err := o.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	got := metricValueForPid(t, exp.lastMetrics(t), metricBitrate, pid)
+	want := bitrate(packetCount*packet.PacketSize, time.Second)
+	if diff := got - want; diff > want*0.1 || diff < -want*0.1 {
+		t.Errorf("bitrate = %v, want ~%v", got, want)
+	}
+
+	// The windowed byte counter resets on Flush, so a second flush with no
+	// new packets reports zero bitrate rather than the same value again.
+	o.mu.Lock()
+	o.lastFlush = time.Now().Add(-1 * time.Second)
+	o.mu.Unlock()
+	if err := o.Flush(); err != nil {
+		t.Fatalf("second Flush: %v", err)
+	}
+	if got := metricValueForPid(t, exp.lastMetrics(t), metricBitrate, pid); got != 0 {
+		t.Errorf("bitrate after reset = %v, want 0", got)
+	}
+}